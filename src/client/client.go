@@ -1,9 +1,13 @@
 package client
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"os"
 	"personal/discord_go/src/opcodes"
@@ -12,11 +16,11 @@ import (
 	"github.com/gorilla/websocket"
 )
 
-const DISCORD_API = "https://discordapp.com/api/v6"
+const DiscordAPI = "https://discordapp.com/api/v6"
 
-func NewBot() *Client {
+func NewBot(opts ...ClientOption) *Client {
 	// make http request to DISCORD_API/gateway
-	requestURL := DISCORD_API + "/gateway"
+	requestURL := DiscordAPI + "/gateway"
 
 	req, err := http.NewRequest("GET", requestURL, nil)
 	if err != nil {
@@ -46,60 +50,127 @@ func NewBot() *Client {
 		os.Exit(1)
 	}
 
-	return &Client{gateway: response.Url, sequence: -1}
-}
-
-func (c *Client) ConnectToGateway(token string) {
-	if c.gateway == "" {
-		fmt.Println("Gateway not set")
-		return
+	c := &Client{
+		gateway:     response.Url,
+		sequence:    -1,
+		httpClient:  &http.Client{},
+		rateLimiter: newRateLimiter(),
+		dispatcher:  newDispatcher(),
+		compression: CompressionZlibStream,
 	}
 
-	conn, _, err := websocket.DefaultDialer.Dial(c.gateway, http.Header{})
-	if err != nil {
-		fmt.Printf("ConnectToGateway: could not connect to WebSocket: %s\n", err)
-		return
+	for _, opt := range opts {
+		opt(c)
 	}
 
-	c.connection = conn
+	return c
+}
 
-	// First message should be hello
-	_, messageBody, err := c.connection.ReadMessage()
-	if err != nil {
-		fmt.Printf("ConnectToGateway: could not receive message from WebSocket: %s\n", err)
-		return
-	}
+// doRequest issues a rate-limited REST request: it waits for the route's
+// bucket to have room, sends the request, and updates the bucket from the
+// response's X-RateLimit-* headers. On a 429 it honors Retry-After (and, for
+// a global rate limit, blocks every other in-flight request until it's over)
+// and retries automatically.
+func (c *Client) doRequest(ctx context.Context, method, route string, body []byte) (*http.Response, error) {
+	routeKey := normalizeRoute(method, route)
 
-	// Unmarshal message body
-	var message HelloMessage
-	err = json.Unmarshal(messageBody, &message)
-	if err != nil {
-		fmt.Printf("ConnectToGateway: could not unmarshal message body: %s\n", err)
-		return
+	for {
+		if err := c.rateLimiter.wait(ctx, routeKey); err != nil {
+			return nil, fmt.Errorf("doRequest: %w", err)
+		}
+
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, DiscordAPI+route, reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("doRequest: could not create request: %w", err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bot %s", c.token))
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		c.mu.RLock()
+		httpClient := c.httpClient
+		c.mu.RUnlock()
+
+		c.rateLimiter.globalLock.RLock()
+		res, err := httpClient.Do(req)
+		c.rateLimiter.globalLock.RUnlock()
+		if err != nil {
+			return nil, fmt.Errorf("doRequest: error making http request: %w", err)
+		}
+
+		c.rateLimiter.update(routeKey, res.Header)
+
+		if res.StatusCode != http.StatusTooManyRequests {
+			return res, nil
+		}
+
+		retryAfter := parseRetryAfter(res.Header)
+		res.Body.Close()
+
+		if res.Header.Get("X-RateLimit-Global") == "true" {
+			fmt.Printf("doRequest: globally rate limited, retrying in %s\n", retryAfter)
+			c.rateLimiter.globalLock.Lock()
+			select {
+			case <-time.After(retryAfter):
+			case <-ctx.Done():
+				c.rateLimiter.globalLock.Unlock()
+				return nil, ctx.Err()
+			}
+			c.rateLimiter.globalLock.Unlock()
+			continue
+		}
+
+		fmt.Printf("doRequest: rate limited on %s, retrying in %s\n", routeKey, retryAfter)
+		select {
+		case <-time.After(retryAfter):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
+}
 
-	if message.Op != opcodes.Hello {
-		fmt.Printf("ConnectToGateway: invalid handshake, expected Hello, got %d\n", message.Op)
+// ConnectToGateway starts the gateway lifecycle for the bot: it connects,
+// identifies, and then keeps the session alive, transparently reconnecting
+// (resuming where possible) until Stop is called. It blocks until then.
+func (c *Client) ConnectToGateway(token string) {
+	if c.gateway == "" {
+		fmt.Println("ConnectToGateway: gateway not set")
 		return
 	}
 
-	c.heartbeatInterval = message.D.HeartbeatInterval
-	c.setHeartbeatInterval(c.heartbeatInterval)
-	fmt.Printf("ConnectToGateway: Successfully made handshake; heartbeat interval: %d\n", c.heartbeatInterval)
+	c.token = token
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
 
-	c.Identify(token)
+	c.run(ctx)
 }
 
-func (c *Client) Identify(token string) {
-	if c.connection == nil {
-		fmt.Println("Identify: connection is not open")
-		return
+// Stop tears down the gateway connection and all of its supporting
+// goroutines (read loop, heartbeat, reconnect controller).
+func (c *Client) Stop() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.dispatcher.stop()
+}
+
+func (c *Client) sendIdentify() error {
+	shardCount := c.numShards
+	if shardCount == 0 {
+		shardCount = 1
 	}
 
 	identifyMessage := IdentifyMessage{
 		Op: opcodes.Identify,
 		D: IdentifyData{
-			Token: token,
+			Token: c.token,
 			Properties: struct {
 				Os      string `json:"$os"`
 				Browser string `json:"$browser"`
@@ -109,55 +180,65 @@ func (c *Client) Identify(token string) {
 				Browser: "discord_go",
 				Device:  "discord_go",
 			},
-			Shard: []int{0, 1},
+			// Stream compression (zlib-stream/zstd-stream) is negotiated via
+			// the gateway URL's compress param, not this flag; it only
+			// applies to Discord's (unsupported) per-packet compression mode.
+			Compress: false,
+			Shard:    []int{c.shardID, shardCount},
 		},
 	}
 
 	identifyMessageBody, err := json.Marshal(identifyMessage)
-
 	if err != nil {
-		fmt.Printf("Identify: could not marshal identify message: %s\n", err)
-		return
+		return fmt.Errorf("sendIdentify: could not marshal identify message: %w", err)
 	}
 
-	err = c.connection.WriteMessage(websocket.TextMessage, identifyMessageBody)
+	if err := c.connection.WriteMessage(websocket.TextMessage, identifyMessageBody); err != nil {
+		return fmt.Errorf("sendIdentify: could not send identify message: %w", err)
+	}
 
+	fmt.Println("sendIdentify: sent identify message")
+	return nil
+}
+
+func (c *Client) sendResume() error {
+	resumeMessage := ResumeMessage{
+		Op: opcodes.Resume,
+		D: ResumeData{
+			Token:     c.token,
+			SessionID: c.sessionId,
+			Sequence:  c.getSequence(),
+		},
+	}
+
+	resumeMessageBody, err := json.Marshal(resumeMessage)
 	if err != nil {
-		fmt.Printf("Identify: could not send identify message: %s\n", err)
-		return
+		return fmt.Errorf("sendResume: could not marshal resume message: %w", err)
 	}
 
-	fmt.Println("Identify: sent identify message")
+	if err := c.connection.WriteMessage(websocket.TextMessage, resumeMessageBody); err != nil {
+		return fmt.Errorf("sendResume: could not send resume message: %w", err)
+	}
 
-	c.StartListening()
+	fmt.Println("sendResume: sent resume message")
+	return nil
 }
 
 func (c *Client) SendHeartbeat() {
-	if !c.lastHeartbeatAcked {
-		// TODO: handle this
-
-		fmt.Println("SendHeartbeat: Last heartbeat was not acknowledged, reconnecting")
-		return
-	}
-
-	c.lastHeartbeatAcked = false
-	c.lastHeartbeatTimestamp = time.Now().UnixNano() / int64(time.Millisecond)
+	c.setHeartbeatAcked(false)
 
 	heartbeatMessage := HeartbeatMessage{
 		Op: opcodes.Heartbeat,
-		D:  c.sequence,
+		D:  c.getSequence(),
 	}
 
 	heartbeatMessageBody, err := json.Marshal(heartbeatMessage)
-
 	if err != nil {
 		fmt.Printf("SendHeartbeat: could not marshal heartbeat message: %s\n", err)
 		return
 	}
 
-	err = c.connection.WriteMessage(websocket.TextMessage, heartbeatMessageBody)
-
-	if err != nil {
+	if err := c.connection.WriteMessage(websocket.TextMessage, heartbeatMessageBody); err != nil {
 		fmt.Printf("SendHeartbeat: could not send heartbeat message: %s\n", err)
 		return
 	}
@@ -165,97 +246,264 @@ func (c *Client) SendHeartbeat() {
 	fmt.Println("SendHeartbeat: sent heartbeat message")
 }
 
-func (c *Client) setHeartbeatInterval(timeToWait int) {
-	if timeToWait == -1 {
-		// Stop timer
-		c.heartbeatTimer.Stop()
+// heartbeatLoop fires a heartbeat every c.heartbeatInterval, starting with a
+// jittered first beat as recommended by the gateway docs. If the previous
+// heartbeat was never acked by the time the next one is due, the connection
+// is considered zombied and is force-closed with code 4000 so the reconnect
+// controller resumes.
+func (c *Client) heartbeatLoop(ctx context.Context) {
+	interval := time.Duration(c.heartbeatInterval) * time.Millisecond
+
+	select {
+	case <-time.After(time.Duration(rand.Float64() * float64(interval))):
+	case <-ctx.Done():
 		return
 	}
+	c.SendHeartbeat()
 
-	fmt.Printf("setHeartbeatInterval: setting heartbeat timer to %d milliseconds \n", timeToWait)
-	c.heartbeatTimer = time.AfterFunc(time.Duration(timeToWait)*time.Millisecond, func() {
-		c.SendHeartbeat()
-		c.setHeartbeatInterval(timeToWait)
-	})
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !c.heartbeatAcked() {
+				fmt.Println("heartbeatLoop: zombied connection detected, forcing reconnect")
+				c.forceClose(CloseUnknownError)
+				return
+			}
+			c.SendHeartbeat()
+		}
+	}
 }
 
-func (c *Client) StartListening() {
-	if c.connection == nil {
-		fmt.Println("StartListening: connection is not open")
+func (c *Client) forceClose(code CloseCode) {
+	c.mu.Lock()
+	c.forcedCloseCode = &code
+	conn := c.connection
+	c.mu.Unlock()
+
+	if conn == nil {
 		return
 	}
 
-	fmt.Println("StartListening: started listening for messages")
+	deadline := time.Now().Add(time.Second)
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(int(code), "zombied connection"), deadline)
+	_ = conn.Close()
+}
+
+func (c *Client) AcknowledgeHeartbeat() {
+	fmt.Println("AcknowledgeHeartbeat: received heartbeat ACK")
+	c.setHeartbeatAcked(true)
+}
+
+// setHeartbeatAcked records whether the most recently sent heartbeat has
+// been acknowledged and when that state last changed. It is called from
+// both the heartbeatLoop goroutine and the StartListening read loop, so
+// access to the underlying fields is guarded by c.mu.
+func (c *Client) setHeartbeatAcked(acked bool) {
+	c.mu.Lock()
+	c.lastHeartbeatAcked = acked
+	c.lastHeartbeatTimestamp = time.Now().UnixNano() / int64(time.Millisecond)
+	c.mu.Unlock()
+}
+
+func (c *Client) heartbeatAcked() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastHeartbeatAcked
+}
+
+// getSequence and setSequence guard c.sequence, which is read by the
+// heartbeat/resume senders and written from handlePacket on the read loop.
+func (c *Client) getSequence() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sequence
+}
+
+func (c *Client) setSequence(s int64) {
+	c.mu.Lock()
+	c.sequence = s
+	c.mu.Unlock()
+}
+
+// getUserID and setUserID guard c.userID, which is written from
+// handleDispatch on every READY and read from the dispatcher's worker
+// goroutines (JoinVoiceChannel and its event handlers).
+func (c *Client) getUserID() Snowflake {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.userID
+}
+
+func (c *Client) setUserID(id Snowflake) {
+	c.mu.Lock()
+	c.userID = id
+	c.mu.Unlock()
+}
+
+func (c *Client) GetGateway() string {
+	return c.gateway
+}
+
+// readGatewayMessage reads one logical gateway message off the connection.
+// Under zlib-stream compression, Discord sends binary frames that must be
+// accumulated until a ZLIB_SUFFIX marks a flush point before they can be
+// decompressed into a JSON payload; uncompressed (text) messages pass
+// through untouched.
+func (c *Client) readGatewayMessage() ([]byte, error) {
+	for {
+		messageType, data, err := c.connection.ReadMessage()
+		if err != nil {
+			return nil, err
+		}
+
+		if messageType != websocket.BinaryMessage {
+			return data, nil
+		}
+
+		c.frameBuffer.Write(data)
+
+		buffered := c.frameBuffer.Bytes()
+		if len(buffered) < len(zlibSuffix) || !bytes.Equal(buffered[len(buffered)-len(zlibSuffix):], zlibSuffix) {
+			continue
+		}
+
+		frame := append([]byte(nil), buffered...)
+		c.frameBuffer.Reset()
+
+		return c.zlib.decompress(frame)
+	}
+}
+
+// StartListening owns a single connection's lifetime: it runs the read loop
+// and heartbeat loop as separate goroutines under connCtx, and dispatches
+// incoming packets until the connection drops, the server asks us to
+// reconnect or invalidates the session, or the parent context is cancelled.
+func (c *Client) StartListening(ctx context.Context) (connResult, error) {
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	c.messageChannel = make(chan []byte)
+	c.forcedCloseCode = nil
+
+	readDone := make(chan struct{})
+	var closeCode CloseCode
+	var closeErr error
 
 	go func() {
+		defer close(readDone)
 		for {
-			_, messageBody, err := c.connection.ReadMessage()
+			messageBody, err := c.readGatewayMessage()
 			if err != nil {
-				fmt.Printf("StartListening: could not receive message from WebSocket: %s\n", err)
+				c.mu.RLock()
+				forced := c.forcedCloseCode
+				c.mu.RUnlock()
+
+				if forced != nil {
+					closeCode, closeErr = *forced, err
+				} else {
+					closeCode, closeErr = classifyCloseError(err)
+				}
 				close(c.messageChannel)
 				return
 			}
 
-			c.messageChannel <- messageBody
+			select {
+			case c.messageChannel <- messageBody:
+			case <-connCtx.Done():
+				return
+			}
 		}
 	}()
 
+	go c.heartbeatLoop(connCtx)
+
 	for {
 		select {
-		case messageBody := <-c.messageChannel:
-			var message Packet
-
-			err := json.Unmarshal(messageBody, &message)
+		case <-ctx.Done():
+			_ = c.connection.Close()
+			cancel()
+			<-readDone
+			return connResult{}, nil
+		case messageBody, ok := <-c.messageChannel:
+			if !ok {
+				<-readDone
+				return connResult{resume: closeCode.resumable()}, closeErr
+			}
 
+			result, err := c.handlePacket(messageBody)
 			if err != nil {
-				fmt.Printf("StartListening: could not unmarshal message body: %s\n", err)
-				fmt.Println(string(messageBody))
-				return
+				fmt.Printf("StartListening: %s\n", err)
+				continue
+			}
+			if result != nil {
+				cancel()
+				_ = c.connection.Close()
+				<-readDone
+				return *result, nil
 			}
+		}
+	}
+}
 
-			switch message.T {
-			case "READY":
-				fmt.Println("StartListening: received READY event")
-				var data ReadyData
-				err := json.Unmarshal(message.D, &data)
+func (c *Client) handlePacket(messageBody []byte) (*connResult, error) {
+	var message Packet
+	if err := json.Unmarshal(messageBody, &message); err != nil {
+		return nil, fmt.Errorf("handlePacket: could not unmarshal message body: %w", err)
+	}
 
-				if err != nil {
-					fmt.Printf("StartListening: could not unmarshal READY event data: %s\n", err)
-					return
-				}
+	if message.S > c.getSequence() {
+		c.setSequence(message.S)
+	}
 
-				c.sessionId = ReadyData.SessionId
-				c.resumeUrl = ReadyData.ResumeUrl
-				c.lastHeartbeatAcked = true
-				c.SendHeartbeat()
-			default:
-				// TODO: resumed event
-			}
+	switch message.Op {
+	case opcodes.Dispatch:
+		c.handleDispatch(message)
+	case opcodes.Heartbeat:
+		c.SendHeartbeat()
+	case opcodes.HeartbeatACK:
+		c.AcknowledgeHeartbeat()
+	case opcodes.Reconnect:
+		fmt.Println("handlePacket: server requested reconnect")
+		return &connResult{resume: true}, nil
+	case opcodes.InvalidSession:
+		var resumable bool
+		_ = json.Unmarshal(message.D, &resumable)
+		fmt.Printf("handlePacket: invalid session, resumable: %t\n", resumable)
+		return &connResult{resume: resumable, delay: randomDelay(1*time.Second, 5*time.Second)}, nil
+	default:
+		fmt.Printf("handlePacket: received unhandled opcode: %d\n", message.Op)
+	}
 
-			switch message.Op {
-			case opcodes.HeartbeatACK:
-				c.AcknowledgeHeartbeat()
-			case opcodes.Heartbeat:
-				c.SendHeartbeat()
-			default:
-				fmt.Printf("StartListening: received unknown message type: %d\n", message.Op)
-			}
+	return nil, nil
+}
 
-			if message.S > c.sequence {
-				c.sequence = message.S
-			}
-		}
+func (c *Client) handleDispatch(message Packet) {
+	event, err := unmarshalEvent(message.T, message.D, c.shardID)
+	if err != nil {
+		fmt.Printf("handleDispatch: %s\n", err)
+		return
 	}
-}
 
-func (c *Client) AcknowledgeHeartbeat() {
-	fmt.Println("AcknowledgeHeartbeat: received heartbeat ACK")
-	c.lastHeartbeatAcked = true
-	c.lastHeartbeatTimestamp = time.Now().UnixNano() / int64(time.Millisecond)
+	if ready, ok := event.(ReadyEvent); ok {
+		fmt.Println("handleDispatch: received READY event")
+		c.sessionId = ready.SessionId
+		c.resumeUrl = ready.ResumeUrl
+		c.setUserID(ready.User.ID)
+	}
+
+	// Hand off rather than call dispatch inline: the jobs channel can fill up
+	// while handlers are slow, and this loop must keep draining messageChannel.
+	go c.dispatcher.dispatch(event)
 }
 
-func (c *Client) GetGateway() string {
-	return c.gateway
+func classifyCloseError(err error) (CloseCode, error) {
+	if closeErr, ok := err.(*websocket.CloseError); ok {
+		return CloseCode(closeErr.Code), err
+	}
+	return CloseUnknownError, err
 }
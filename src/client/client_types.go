@@ -41,6 +41,7 @@ type IdentifyData struct {
 type ReadyData struct {
 	SessionId string `json:"session_id"`
 	ResumeUrl string `json:"resume_gateway_url"`
+	User      User   `json:"user"`
 }
 
 type HeartbeatMessage struct {
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+)
+
+// Compression selects how the gateway connection negotiates payload
+// compression via the `compress` query parameter.
+type Compression string
+
+const (
+	CompressionNone       Compression = ""
+	CompressionZlibStream Compression = "zlib-stream"
+	// CompressionZstdStream is not implemented yet: Discord's zstd-stream
+	// transport compression needs a zstd decoder we don't have wired up.
+	CompressionZstdStream Compression = "zstd-stream"
+)
+
+// ClientOption configures a Client at construction time, see NewBot.
+type ClientOption func(*Client)
+
+// WithCompression selects the gateway transport compression to negotiate.
+// Defaults to CompressionZlibStream.
+func WithCompression(compression Compression) ClientOption {
+	return func(c *Client) {
+		c.compression = compression
+	}
+}
+
+// zlibSuffix marks the end of a zlib-stream flush point, per
+// https://discord.com/developers/docs/events/gateway#transport-compression.
+var zlibSuffix = []byte{0x00, 0x00, 0xff, 0xff}
+
+// frameSource is the io.Reader fed to the persistent zlib.Reader. It hands
+// out one frame's compressed bytes at a time and reports io.EOF as soon as
+// that frame is drained, which is exactly the signal decompress needs to
+// know it has read everything the sync-flush boundary made available -
+// without guessing based on how much was returned from a single Read call.
+type frameSource struct {
+	remaining []byte
+}
+
+func (f *frameSource) setFrame(frame []byte) {
+	f.remaining = frame
+}
+
+func (f *frameSource) Read(p []byte) (int, error) {
+	if len(f.remaining) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, f.remaining)
+	f.remaining = f.remaining[n:]
+	return n, nil
+}
+
+// zlibStream decompresses Discord's zlib-stream transport compression. Per
+// Discord's docs the entire connection is a single zlib context, not one per
+// frame, so the underlying zlib.Reader is created once per WebSocket
+// connection and fed every frame in order.
+type zlibStream struct {
+	src *frameSource
+	zr  io.ReadCloser
+	out bytes.Buffer
+}
+
+func newZlibStream() *zlibStream {
+	return &zlibStream{src: &frameSource{}}
+}
+
+// decompress flushes one zlib-stream frame (the bytes accumulated up to and
+// including a ZLIB_SUFFIX) through the persistent zlib context and returns
+// the decompressed JSON payload. The returned slice is only valid until the
+// next call to decompress.
+func (z *zlibStream) decompress(frame []byte) ([]byte, error) {
+	z.src.setFrame(frame)
+
+	if z.zr == nil {
+		zr, err := zlib.NewReader(z.src)
+		if err != nil {
+			return nil, fmt.Errorf("zlibStream: could not initialize zlib reader: %w", err)
+		}
+		z.zr = zr
+	}
+
+	z.out.Reset()
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := z.zr.Read(buf)
+		if n > 0 {
+			z.out.Write(buf[:n])
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("zlibStream: could not decompress frame: %w", err)
+		}
+	}
+
+	return z.out.Bytes(), nil
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"bytes"
+	"compress/zlib"
+	"strings"
+	"testing"
+)
+
+// zlibStreamFrames compresses payloads through a single zlib.Writer and
+// flushes after each one, mirroring how Discord emits one zlib-stream frame
+// per gateway message over a shared connection-lifetime zlib context.
+func zlibStreamFrames(t *testing.T, payloads ...[]byte) [][]byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+
+	frames := make([][]byte, 0, len(payloads))
+	for _, p := range payloads {
+		buf.Reset()
+		if _, err := zw.Write(p); err != nil {
+			t.Fatalf("could not write payload: %s", err)
+		}
+		if err := zw.Flush(); err != nil {
+			t.Fatalf("could not flush zlib writer: %s", err)
+		}
+		frame := make([]byte, buf.Len())
+		copy(frame, buf.Bytes())
+		frames = append(frames, frame)
+	}
+
+	return frames
+}
+
+func TestZlibStreamDecompressSingleFrame(t *testing.T) {
+	payload := []byte(`{"op":0,"t":"READY"}`)
+	frames := zlibStreamFrames(t, payload)
+
+	z := newZlibStream()
+	got, err := z.decompress(frames[0])
+	if err != nil {
+		t.Fatalf("decompress: %s", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("decompress() = %q, want %q", got, payload)
+	}
+}
+
+// TestZlibStreamDecompressLargeFrame guards against the short-read
+// heuristic regression: a frame whose decompressed output is larger than
+// compress/flate's internal read buffer must still come back whole instead
+// of being silently truncated.
+func TestZlibStreamDecompressLargeFrame(t *testing.T) {
+	payload := []byte(strings.Repeat("a", 131072))
+	frames := zlibStreamFrames(t, payload)
+
+	z := newZlibStream()
+	got, err := z.decompress(frames[0])
+	if err != nil {
+		t.Fatalf("decompress: %s", err)
+	}
+	if len(got) != len(payload) {
+		t.Fatalf("decompress() returned %d bytes, want %d", len(got), len(payload))
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("decompress() did not round-trip the large payload")
+	}
+}
+
+// TestZlibStreamDecompressMultipleFrames verifies that a single zlibStream
+// can decompress several frames in order over the connection's lifetime,
+// and that a large frame doesn't hang or corrupt the frame that follows it.
+func TestZlibStreamDecompressMultipleFrames(t *testing.T) {
+	payloads := [][]byte{
+		[]byte(strings.Repeat("b", 131072)),
+		[]byte(`{"op":0,"t":"MESSAGE_CREATE"}`),
+	}
+	frames := zlibStreamFrames(t, payloads...)
+
+	z := newZlibStream()
+	for i, frame := range frames {
+		got, err := z.decompress(frame)
+		if err != nil {
+			t.Fatalf("decompress frame %d: %s", i, err)
+		}
+		if !bytes.Equal(got, payloads[i]) {
+			t.Errorf("frame %d: decompress() = %q, want %q", i, got, payloads[i])
+		}
+	}
+}
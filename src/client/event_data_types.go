@@ -0,0 +1,64 @@
+package client
+
+import "encoding/json"
+
+type Message struct {
+	ID              Snowflake  `json:"id"`
+	ChannelID       Snowflake  `json:"channel_id"`
+	GuildID         *Snowflake `json:"guild_id,omitempty"`
+	Author          User       `json:"author"`
+	Content         string     `json:"content"`
+	Timestamp       string     `json:"timestamp"`
+	EditedTimestamp *string    `json:"edited_timestamp,omitempty"`
+	TTS             bool       `json:"tts"`
+	MentionEveryone bool       `json:"mention_everyone"`
+	Mentions        []User     `json:"mentions"`
+	Pinned          bool       `json:"pinned"`
+	Type            int        `json:"type"`
+}
+
+type MessageDeleteData struct {
+	ID        Snowflake  `json:"id"`
+	ChannelID Snowflake  `json:"channel_id"`
+	GuildID   *Snowflake `json:"guild_id,omitempty"`
+}
+
+type Guild struct {
+	ID          Snowflake  `json:"id"`
+	Name        string     `json:"name"`
+	Icon        *string    `json:"icon,omitempty"`
+	OwnerID     Snowflake  `json:"owner_id"`
+	MemberCount *int       `json:"member_count,omitempty"`
+	Channels    []Channel  `json:"channels,omitempty"`
+	Unavailable *bool      `json:"unavailable,omitempty"`
+}
+
+type VoiceState struct {
+	GuildID   *Snowflake `json:"guild_id,omitempty"`
+	ChannelID *Snowflake `json:"channel_id,omitempty"`
+	UserID    Snowflake  `json:"user_id"`
+	SessionID string     `json:"session_id"`
+	Deaf      bool       `json:"deaf"`
+	Mute      bool       `json:"mute"`
+	SelfDeaf  bool       `json:"self_deaf"`
+	SelfMute  bool       `json:"self_mute"`
+}
+
+type VoiceServerUpdateData struct {
+	Token    string    `json:"token"`
+	GuildID  Snowflake `json:"guild_id"`
+	Endpoint *string   `json:"endpoint"`
+}
+
+type Interaction struct {
+	ID            Snowflake       `json:"id"`
+	ApplicationID Snowflake       `json:"application_id"`
+	Type          int             `json:"type"`
+	Data          json.RawMessage `json:"data,omitempty"`
+	GuildID       *Snowflake      `json:"guild_id,omitempty"`
+	ChannelID     *Snowflake      `json:"channel_id,omitempty"`
+	Member        *ThreadMember   `json:"member,omitempty"`
+	User          *User           `json:"user,omitempty"`
+	Token         string          `json:"token"`
+	Version       int             `json:"version"`
+}
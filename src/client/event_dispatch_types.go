@@ -0,0 +1,136 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"personal/discord_go/src/opcodes"
+)
+
+type ReadyEvent struct {
+	baseEvent
+	ReadyData
+}
+
+type ResumedEvent struct {
+	baseEvent
+}
+
+type MessageCreateEvent struct {
+	baseEvent
+	Message
+}
+
+type MessageUpdateEvent struct {
+	baseEvent
+	Message
+}
+
+type MessageDeleteEvent struct {
+	baseEvent
+	MessageDeleteData
+}
+
+type GuildCreateEvent struct {
+	baseEvent
+	Guild
+}
+
+type ChannelCreateEvent struct {
+	baseEvent
+	Channel
+}
+
+type InteractionCreateEvent struct {
+	baseEvent
+	Interaction
+}
+
+type VoiceStateUpdateEvent struct {
+	baseEvent
+	VoiceState
+}
+
+type VoiceServerUpdateEvent struct {
+	baseEvent
+	VoiceServerUpdateData
+}
+
+// eventUnmarshalers maps a dispatch event's T field to a function that
+// decodes its D payload into the matching concrete Event type. Add a new
+// case here (and a concrete type above) to support another dispatch event.
+var eventUnmarshalers = map[string]func(d json.RawMessage, shardID int) (Event, error){
+	"READY": func(d json.RawMessage, shardID int) (Event, error) {
+		var data ReadyData
+		if err := json.Unmarshal(d, &data); err != nil {
+			return nil, err
+		}
+		return ReadyEvent{baseEvent: baseEvent{op: opcodes.Dispatch, t: "READY", shard: shardID}, ReadyData: data}, nil
+	},
+	"RESUMED": func(d json.RawMessage, shardID int) (Event, error) {
+		return ResumedEvent{baseEvent: baseEvent{op: opcodes.Dispatch, t: "RESUMED", shard: shardID}}, nil
+	},
+	"MESSAGE_CREATE": func(d json.RawMessage, shardID int) (Event, error) {
+		var data Message
+		if err := json.Unmarshal(d, &data); err != nil {
+			return nil, err
+		}
+		return MessageCreateEvent{baseEvent: baseEvent{op: opcodes.Dispatch, t: "MESSAGE_CREATE", shard: shardID}, Message: data}, nil
+	},
+	"MESSAGE_UPDATE": func(d json.RawMessage, shardID int) (Event, error) {
+		var data Message
+		if err := json.Unmarshal(d, &data); err != nil {
+			return nil, err
+		}
+		return MessageUpdateEvent{baseEvent: baseEvent{op: opcodes.Dispatch, t: "MESSAGE_UPDATE", shard: shardID}, Message: data}, nil
+	},
+	"MESSAGE_DELETE": func(d json.RawMessage, shardID int) (Event, error) {
+		var data MessageDeleteData
+		if err := json.Unmarshal(d, &data); err != nil {
+			return nil, err
+		}
+		return MessageDeleteEvent{baseEvent: baseEvent{op: opcodes.Dispatch, t: "MESSAGE_DELETE", shard: shardID}, MessageDeleteData: data}, nil
+	},
+	"GUILD_CREATE": func(d json.RawMessage, shardID int) (Event, error) {
+		var data Guild
+		if err := json.Unmarshal(d, &data); err != nil {
+			return nil, err
+		}
+		return GuildCreateEvent{baseEvent: baseEvent{op: opcodes.Dispatch, t: "GUILD_CREATE", shard: shardID}, Guild: data}, nil
+	},
+	"CHANNEL_CREATE": func(d json.RawMessage, shardID int) (Event, error) {
+		var data Channel
+		if err := json.Unmarshal(d, &data); err != nil {
+			return nil, err
+		}
+		return ChannelCreateEvent{baseEvent: baseEvent{op: opcodes.Dispatch, t: "CHANNEL_CREATE", shard: shardID}, Channel: data}, nil
+	},
+	"INTERACTION_CREATE": func(d json.RawMessage, shardID int) (Event, error) {
+		var data Interaction
+		if err := json.Unmarshal(d, &data); err != nil {
+			return nil, err
+		}
+		return InteractionCreateEvent{baseEvent: baseEvent{op: opcodes.Dispatch, t: "INTERACTION_CREATE", shard: shardID}, Interaction: data}, nil
+	},
+	"VOICE_STATE_UPDATE": func(d json.RawMessage, shardID int) (Event, error) {
+		var data VoiceState
+		if err := json.Unmarshal(d, &data); err != nil {
+			return nil, err
+		}
+		return VoiceStateUpdateEvent{baseEvent: baseEvent{op: opcodes.Dispatch, t: "VOICE_STATE_UPDATE", shard: shardID}, VoiceState: data}, nil
+	},
+	"VOICE_SERVER_UPDATE": func(d json.RawMessage, shardID int) (Event, error) {
+		var data VoiceServerUpdateData
+		if err := json.Unmarshal(d, &data); err != nil {
+			return nil, err
+		}
+		return VoiceServerUpdateEvent{baseEvent: baseEvent{op: opcodes.Dispatch, t: "VOICE_SERVER_UPDATE", shard: shardID}, VoiceServerUpdateData: data}, nil
+	},
+}
+
+func unmarshalEvent(eventName string, data json.RawMessage, shardID int) (Event, error) {
+	unmarshal, ok := eventUnmarshalers[eventName]
+	if !ok {
+		return nil, fmt.Errorf("unmarshalEvent: no handler registered for event type %q", eventName)
+	}
+	return unmarshal(data, shardID)
+}
@@ -0,0 +1,162 @@
+package client
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Event is implemented by every concrete gateway dispatch event, mirroring
+// how arikawa/discordgo expose typed events to consumers.
+type Event interface {
+	Op() int
+	EventType() string
+	ShardID() int
+}
+
+type baseEvent struct {
+	op    int
+	t     string
+	shard int
+}
+
+func (e baseEvent) Op() int           { return e.op }
+func (e baseEvent) EventType() string { return e.t }
+func (e baseEvent) ShardID() int      { return e.shard }
+
+var (
+	eventType = reflect.TypeOf((*Event)(nil)).Elem()
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// handler is a registered callback: either typed (typ set to a concrete
+// Event implementation), catch-all (typ == nil), or an error handler
+// (isError == true).
+type handler struct {
+	fn      reflect.Value
+	typ     reflect.Type
+	isError bool
+}
+
+const dispatchWorkers = 4
+
+// Dispatcher fans dispatch events out to registered handlers on a small
+// worker pool, so a slow handler can't stall the gateway read loop.
+type Dispatcher struct {
+	mu       sync.RWMutex
+	nextID   int
+	handlers map[int]handler
+
+	jobs     chan Event
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newDispatcher() *Dispatcher {
+	d := &Dispatcher{
+		handlers: make(map[int]handler),
+		jobs:     make(chan Event, 64),
+		done:     make(chan struct{}),
+	}
+	for i := 0; i < dispatchWorkers; i++ {
+		go d.worker()
+	}
+	return d
+}
+
+func (d *Dispatcher) worker() {
+	for {
+		select {
+		case event := <-d.jobs:
+			d.mu.RLock()
+			matched := make([]handler, 0, len(d.handlers))
+			for _, h := range d.handlers {
+				if h.isError {
+					continue
+				}
+				if h.typ != nil && h.typ != reflect.TypeOf(event) {
+					continue
+				}
+				matched = append(matched, h)
+			}
+			d.mu.RUnlock()
+
+			for _, h := range matched {
+				h.fn.Call([]reflect.Value{reflect.ValueOf(event)})
+			}
+		case <-d.done:
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch(event Event) {
+	select {
+	case d.jobs <- event:
+	case <-d.done:
+	}
+}
+
+// stop shuts down the worker pool. It is safe to call more than once and
+// safe to call concurrently with dispatch/dispatchError.
+func (d *Dispatcher) stop() {
+	d.stopOnce.Do(func() {
+		close(d.done)
+	})
+}
+
+func (d *Dispatcher) dispatchError(err error) {
+	d.mu.RLock()
+	matched := make([]handler, 0)
+	for _, h := range d.handlers {
+		if h.isError {
+			matched = append(matched, h)
+		}
+	}
+	d.mu.RUnlock()
+
+	for _, h := range matched {
+		h.fn.Call([]reflect.Value{reflect.ValueOf(err)})
+	}
+}
+
+func (d *Dispatcher) add(h handler) func() {
+	d.mu.Lock()
+	id := d.nextID
+	d.nextID++
+	d.handlers[id] = h
+	d.mu.Unlock()
+
+	return func() {
+		d.mu.Lock()
+		delete(d.handlers, id)
+		d.mu.Unlock()
+	}
+}
+
+// AddHandler registers fn to be called whenever a matching event is
+// dispatched. fn must be a func taking exactly one argument: a concrete
+// Event type (e.g. func(client.MessageCreateEvent)), the Event interface
+// itself as a catch-all, or error to be notified of gateway errors. It
+// returns a function that removes the handler.
+func (c *Client) AddHandler(fn interface{}) func() {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+
+	if t.Kind() != reflect.Func || t.NumIn() != 1 || t.NumOut() != 0 {
+		panic("AddHandler: handler must be a func with exactly one parameter and no return value")
+	}
+
+	paramType := t.In(0)
+
+	switch {
+	case paramType == errorType:
+		return c.dispatcher.add(handler{fn: v, isError: true})
+	case paramType == eventType:
+		return c.dispatcher.add(handler{fn: v})
+	case paramType.Implements(eventType):
+		return c.dispatcher.add(handler{fn: v, typ: paramType})
+	default:
+		panic(fmt.Sprintf("AddHandler: %s is not an Event, error, or the Event interface", paramType))
+	}
+}
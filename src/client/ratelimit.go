@@ -0,0 +1,148 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// majorRouteParams are the path segments whose following ID is a major
+// rate-limit parameter (Discord buckets by these, not by every ID in the
+// path), per https://discord.com/developers/docs/topics/rate-limits.
+var majorRouteParams = map[string]bool{
+	"channels": true,
+	"guilds":   true,
+	"webhooks": true,
+}
+
+var snowflakeSegment = regexp.MustCompile(`^\d{15,21}$`)
+
+// normalizeRoute collapses minor IDs in a request path into a shared route
+// key (method + path, with non-major snowflakes replaced by ":id") so that
+// two message routes under the same channel ID share a bucket while routes
+// under two different channel IDs do not. Only segments of 15 to 21 digits
+// count as snowflakes; shorter numeric segments are kept as they are.
+func normalizeRoute(method, path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i := 1; i < len(segments); i++ {
+		if !snowflakeSegment.MatchString(segments[i]) {
+			continue
+		}
+		if majorRouteParams[segments[i-1]] {
+			continue
+		}
+		segments[i] = ":id"
+	}
+
+	return method + " " + strings.Join(segments, "/")
+}
+
+// bucket tracks the rate-limit state Discord reports for a route (or, once
+// discovered, for the shared bucket hash several routes fall into).
+type bucket struct {
+	mu        sync.Mutex
+	Remaining int
+	ResetAt   time.Time
+}
+
+// RateLimiter tracks per-route REST rate-limit buckets as well as Discord's
+// global rate limit, so callers of doRequest never see a 429 under normal
+// operation.
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	hashes  map[string]string // route key -> discovered X-RateLimit-Bucket hash
+
+	globalLock sync.RWMutex
+}
+
+func newRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		buckets: make(map[string]*bucket),
+		hashes:  make(map[string]string),
+	}
+}
+
+func (rl *RateLimiter) bucketFor(key string) *bucket {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if hash, ok := rl.hashes[key]; ok {
+		key = hash
+	}
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{Remaining: 1}
+		rl.buckets[key] = b
+	}
+	return b
+}
+
+// wait blocks until the bucket for routeKey has a request slot available,
+// then reserves one.
+func (rl *RateLimiter) wait(ctx context.Context, routeKey string) error {
+	b := rl.bucketFor(routeKey)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.Remaining <= 0 {
+		if wait := time.Until(b.ResetAt); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	if b.Remaining > 0 {
+		b.Remaining--
+	}
+
+	return nil
+}
+
+// update refreshes bucket state from a response's rate-limit headers,
+// remapping routeKey to the bucket hash Discord reports so routes that share
+// a bucket stay in sync with each other.
+func (rl *RateLimiter) update(routeKey string, header http.Header) {
+	if hash := header.Get("X-RateLimit-Bucket"); hash != "" {
+		rl.mu.Lock()
+		rl.hashes[routeKey] = hash
+		rl.mu.Unlock()
+	}
+
+	remainingHeader := header.Get("X-RateLimit-Remaining")
+	resetAfterHeader := header.Get("X-RateLimit-Reset-After")
+	if remainingHeader == "" && resetAfterHeader == "" {
+		return
+	}
+
+	b := rl.bucketFor(routeKey)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if remaining, err := strconv.Atoi(remainingHeader); err == nil {
+		b.Remaining = remaining
+	}
+	if resetAfter, err := strconv.ParseFloat(resetAfterHeader, 64); err == nil {
+		b.ResetAt = time.Now().Add(time.Duration(resetAfter * float64(time.Second)))
+	}
+}
+
+func parseRetryAfter(header http.Header) time.Duration {
+	if v := header.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil {
+			return time.Duration(seconds * float64(time.Second))
+		}
+	}
+	return time.Second
+}
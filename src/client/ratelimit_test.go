@@ -0,0 +1,74 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNormalizeRoute(t *testing.T) {
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		want   string
+	}{
+		{
+			name:   "minor id replaced",
+			method: "GET",
+			path:   "/channels/123456789012345678/messages/234567890123456789",
+			want:   "GET channels/123456789012345678/messages/:id",
+		},
+		{
+			name:   "two routes with different minor ids share a bucket",
+			method: "GET",
+			path:   "/channels/123456789012345678/messages/111111111111111111",
+			want:   "GET channels/123456789012345678/messages/:id",
+		},
+		{
+			name:   "non-major segment is not a snowflake",
+			method: "GET",
+			path:   "/users/@me/guilds",
+			want:   "GET users/@me/guilds",
+		},
+		{
+			name:   "different major id does not collapse",
+			method: "POST",
+			path:   "/guilds/987654321098765432/channels",
+			want:   "POST guilds/987654321098765432/channels",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := normalizeRoute(tc.method, tc.path); got != tc.want {
+				t.Errorf("normalizeRoute(%q, %q) = %q, want %q", tc.method, tc.path, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeRouteDifferentMajorIDsDontShareABucket(t *testing.T) {
+	a := normalizeRoute("GET", "/channels/123456789012345678/messages/111111111111111111")
+	b := normalizeRoute("GET", "/channels/876543210987654321/messages/222222222222222222")
+	if a == b {
+		t.Errorf("routes under different major IDs collapsed to the same bucket: %q", a)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "1.5")
+
+	got := parseRetryAfter(header)
+	if got != 1500*time.Millisecond {
+		t.Errorf("parseRetryAfter() = %s, want 1.5s", got)
+	}
+}
+
+func TestParseRetryAfterMissingHeaderDefaultsToOneSecond(t *testing.T) {
+	got := parseRetryAfter(http.Header{})
+	if got != time.Second {
+		t.Errorf("parseRetryAfter() with no header = %s, want 1s", got)
+	}
+}
@@ -0,0 +1,204 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"personal/discord_go/src/opcodes"
+)
+
+// CloseCode is a Discord gateway WebSocket close code (4000-4014).
+type CloseCode int
+
+const (
+	CloseUnknownError         CloseCode = 4000
+	CloseUnknownOpcode        CloseCode = 4001
+	CloseDecodeError          CloseCode = 4002
+	CloseNotAuthenticated     CloseCode = 4003
+	CloseAuthenticationFailed CloseCode = 4004
+	CloseAlreadyAuthenticated CloseCode = 4005
+	CloseInvalidSeq           CloseCode = 4007
+	CloseRateLimited          CloseCode = 4008
+	CloseSessionTimedOut      CloseCode = 4009
+	CloseInvalidShard         CloseCode = 4010
+	CloseShardingRequired     CloseCode = 4011
+	CloseInvalidAPIVersion    CloseCode = 4012
+	CloseInvalidIntents       CloseCode = 4013
+	CloseDisallowedIntents    CloseCode = 4014
+)
+
+// resumable reports whether a session that was closed with this code is
+// eligible for a Resume, per the gateway docs. 4004 and 4010-4014 indicate a
+// problem with the identify itself, so those require a fresh Identify.
+func (c CloseCode) resumable() bool {
+	switch c {
+	case CloseAuthenticationFailed, CloseInvalidShard, CloseShardingRequired,
+		CloseInvalidAPIVersion, CloseInvalidIntents, CloseDisallowedIntents:
+		return false
+	}
+	return true
+}
+
+// connResult describes how the reconnect controller should proceed after a
+// connection attempt ends.
+type connResult struct {
+	resume bool
+	delay  time.Duration
+}
+
+const (
+	backoffBase = time.Second
+	backoffCap  = 60 * time.Second
+)
+
+// nextBackoff returns a jittered exponential backoff duration for the given
+// (zero-indexed) reconnect attempt: min(cap, base*2^attempt) * (0.5 + rand*0.5).
+func nextBackoff(attempt int) time.Duration {
+	d := float64(backoffBase) * math.Pow(2, float64(attempt))
+	if d > float64(backoffCap) || d <= 0 {
+		d = float64(backoffCap)
+	}
+	jitter := 0.5 + rand.Float64()*0.5
+	return time.Duration(d * jitter)
+}
+
+// randomDelay returns a random duration in [min, max).
+func randomDelay(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+	return min + time.Duration(rand.Int63n(int64(max-min)))
+}
+
+// run is the reconnect controller: it keeps (re)connecting to the gateway,
+// resuming the session where possible, until ctx is cancelled.
+func (c *Client) run(ctx context.Context) {
+	resume := false
+
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		result, err := c.connectAndListen(ctx, resume)
+		if err != nil {
+			fmt.Printf("run: connection ended: %s\n", err)
+			c.dispatcher.dispatchError(err)
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		resume = result.resume
+		if !resume {
+			c.sessionId = ""
+			c.sequence = -1
+		}
+
+		delay := result.delay
+		if delay == 0 {
+			delay = nextBackoff(c.reconnectAttempt)
+			c.reconnectAttempt++
+		}
+		fmt.Printf("run: reconnecting in %s (resume=%t)\n", delay, resume)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+const gatewayVersion = "10"
+
+// gatewayURL appends the gateway version, encoding, and (optional)
+// compression query parameters Discord expects on every gateway connection.
+func gatewayURL(base string, compression Compression) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	q := u.Query()
+	q.Set("v", gatewayVersion)
+	q.Set("encoding", "json")
+	if compression != CompressionNone {
+		q.Set("compress", string(compression))
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// connectAndListen dials the gateway (or, when resuming, the stored
+// resumeUrl), performs the Hello/Identify or Hello/Resume handshake, and then
+// blocks in StartListening until the connection ends.
+func (c *Client) connectAndListen(ctx context.Context, resume bool) (connResult, error) {
+	baseUrl := c.gateway
+	if resume {
+		baseUrl = c.resumeUrl
+	}
+	if baseUrl == "" {
+		return connResult{}, fmt.Errorf("connectAndListen: no gateway url available")
+	}
+
+	dialUrl, err := gatewayURL(baseUrl, c.compression)
+	if err != nil {
+		return connResult{}, fmt.Errorf("connectAndListen: could not build gateway url: %w", err)
+	}
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, dialUrl, http.Header{})
+	if err != nil {
+		return connResult{}, fmt.Errorf("connectAndListen: could not connect to WebSocket: %w", err)
+	}
+
+	c.mu.Lock()
+	c.connection = conn
+	c.zlib = newZlibStream()
+	c.frameBuffer.Reset()
+	c.mu.Unlock()
+
+	messageBody, err := c.readGatewayMessage()
+	if err != nil {
+		return connResult{}, fmt.Errorf("connectAndListen: could not receive hello message: %w", err)
+	}
+
+	var hello HelloMessage
+	if err := json.Unmarshal(messageBody, &hello); err != nil {
+		return connResult{}, fmt.Errorf("connectAndListen: could not unmarshal hello message: %w", err)
+	}
+
+	if hello.Op != opcodes.Hello {
+		return connResult{}, fmt.Errorf("connectAndListen: invalid handshake, expected Hello, got %d", hello.Op)
+	}
+
+	c.heartbeatInterval = hello.D.HeartbeatInterval
+	fmt.Printf("connectAndListen: handshake complete; heartbeat interval: %dms, resume=%t\n", c.heartbeatInterval, resume)
+
+	if resume {
+		err = c.sendResume()
+	} else {
+		if c.identifyGate != nil {
+			if err := c.identifyGate(ctx); err != nil {
+				return connResult{}, fmt.Errorf("connectAndListen: %w", err)
+			}
+		}
+		err = c.sendIdentify()
+	}
+	if err != nil {
+		return connResult{}, err
+	}
+
+	c.reconnectAttempt = 0
+
+	return c.StartListening(ctx)
+}
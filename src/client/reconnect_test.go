@@ -0,0 +1,41 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextBackoffIsPositiveAndBounded(t *testing.T) {
+	for attempt := 0; attempt < 10; attempt++ {
+		d := nextBackoff(attempt)
+		if d <= 0 {
+			t.Fatalf("nextBackoff(%d) = %s, want > 0", attempt, d)
+		}
+		if d > backoffCap {
+			t.Fatalf("nextBackoff(%d) = %s, want <= cap %s", attempt, d, backoffCap)
+		}
+	}
+}
+
+func TestNextBackoffCapsAtHighAttempts(t *testing.T) {
+	d := nextBackoff(30)
+	if d > backoffCap {
+		t.Errorf("nextBackoff(30) = %s, want <= cap %s", d, backoffCap)
+	}
+}
+
+func TestRandomDelayWithinRange(t *testing.T) {
+	min, max := time.Second, 3*time.Second
+	for i := 0; i < 20; i++ {
+		d := randomDelay(min, max)
+		if d < min || d >= max {
+			t.Fatalf("randomDelay(%s, %s) = %s, want within [%s, %s)", min, max, d, min, max)
+		}
+	}
+}
+
+func TestRandomDelayDegenerateRangeReturnsMin(t *testing.T) {
+	if got := randomDelay(time.Second, time.Second); got != time.Second {
+		t.Errorf("randomDelay(1s, 1s) = %s, want 1s", got)
+	}
+}
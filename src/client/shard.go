@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// gatewayBotResponse is the response body of GET /gateway/bot, which returns
+// the recommended shard count alongside the identify rate limit.
+type gatewayBotResponse struct {
+	Url               string `json:"url"`
+	Shards            int    `json:"shards"`
+	SessionStartLimit struct {
+		Total          int `json:"total"`
+		Remaining      int `json:"remaining"`
+		ResetAfter     int `json:"reset_after"`
+		MaxConcurrency int `json:"max_concurrency"`
+	} `json:"session_start_limit"`
+}
+
+func fetchGatewayBot(token string) (*gatewayBotResponse, error) {
+	req, err := http.NewRequest("GET", DiscordAPI+"/gateway/bot", nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetchGatewayBot: could not create request: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bot %s", token))
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetchGatewayBot: error making http request: %w", err)
+	}
+	defer res.Body.Close()
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("fetchGatewayBot: could not read response body: %w", err)
+	}
+
+	var response gatewayBotResponse
+	if err := json.Unmarshal(resBody, &response); err != nil {
+		return nil, fmt.Errorf("fetchGatewayBot: could not unmarshal response body: %w", err)
+	}
+
+	return &response, nil
+}
+
+// identifyInterval is Discord's minimum gap between two IDENTIFYs in the
+// same max_concurrency bucket.
+const identifyInterval = 5 * time.Second
+
+// ShardManager owns one *Client per shard and coordinates their IDENTIFYs so
+// the bot stays within Discord's session_start_limit.max_concurrency.
+type ShardManager struct {
+	token     string
+	shards    []*Client
+	numShards int
+
+	maxConcurrency int
+	identifyMu     sync.Mutex
+	nextIdentify   []time.Time
+}
+
+// NewShardManager builds a ShardManager for the given token. A shardCount of
+// 0 means "ask Discord for the recommended count" via GET /gateway/bot.
+func NewShardManager(token string, shardCount int) *ShardManager {
+	gatewayBot, err := fetchGatewayBot(token)
+	if err != nil {
+		fmt.Printf("NewShardManager: %s\n", err)
+		os.Exit(1)
+	}
+
+	if shardCount == 0 {
+		shardCount = gatewayBot.Shards
+	}
+
+	maxConcurrency := gatewayBot.SessionStartLimit.MaxConcurrency
+	if maxConcurrency == 0 {
+		maxConcurrency = 1
+	}
+
+	sm := &ShardManager{
+		token:          token,
+		numShards:      shardCount,
+		maxConcurrency: maxConcurrency,
+		nextIdentify:   make([]time.Time, maxConcurrency),
+	}
+
+	for shardID := 0; shardID < shardCount; shardID++ {
+		shard := &Client{
+			token:       token,
+			gateway:     gatewayBot.Url,
+			sequence:    -1,
+			httpClient:  &http.Client{},
+			rateLimiter: newRateLimiter(),
+			dispatcher:  newDispatcher(),
+			compression: CompressionZlibStream,
+			shardID:     shardID,
+			numShards:   shardCount,
+		}
+		shard.identifyGate = sm.waitIdentifySlot(shardID)
+		sm.shards = append(sm.shards, shard)
+	}
+
+	return sm
+}
+
+// waitIdentifySlot returns the identifyGate hook for shardID: it blocks
+// until that shard's max_concurrency bucket has gone identifyInterval since
+// its last IDENTIFY.
+func (sm *ShardManager) waitIdentifySlot(shardID int) func(ctx context.Context) error {
+	bucket := shardID % sm.maxConcurrency
+
+	return func(ctx context.Context) error {
+		sm.identifyMu.Lock()
+		now := time.Now()
+		wait := sm.nextIdentify[bucket].Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		sm.nextIdentify[bucket] = now.Add(wait + identifyInterval)
+		sm.identifyMu.Unlock()
+
+		if wait <= 0 {
+			return nil
+		}
+
+		select {
+		case <-time.After(wait):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Start connects every shard to the gateway concurrently; each shard's
+// reconnect controller runs for the lifetime of the manager (or until ctx is
+// cancelled / Stop is called).
+func (sm *ShardManager) Start(ctx context.Context) {
+	for _, shard := range sm.shards {
+		shardCtx, cancel := context.WithCancel(ctx)
+		shard.cancel = cancel
+		go shard.run(shardCtx)
+	}
+}
+
+// Stop tears down every shard's gateway connection.
+func (sm *ShardManager) Stop() {
+	for _, shard := range sm.shards {
+		shard.Stop()
+	}
+}
+
+// AddHandler registers fn on every shard, mirroring Client.AddHandler.
+// Events delivered to fn carry ShardID() so consumers can route them back to
+// the shard that produced them. It returns a function that removes fn from
+// every shard.
+func (sm *ShardManager) AddHandler(fn interface{}) func() {
+	removers := make([]func(), 0, len(sm.shards))
+	for _, shard := range sm.shards {
+		removers = append(removers, shard.AddHandler(fn))
+	}
+
+	return func() {
+		for _, remove := range removers {
+			remove()
+		}
+	}
+}
+
+// ShardForGuild returns the shard responsible for guildID, per Discord's
+// (guild_id >> 22) % num_shards routing formula.
+func (sm *ShardManager) ShardForGuild(guildID Snowflake) (*Client, error) {
+	var id uint64
+	if _, err := fmt.Sscanf(string(guildID), "%d", &id); err != nil {
+		return nil, fmt.Errorf("ShardForGuild: invalid guild id %q: %w", guildID, err)
+	}
+
+	shardID := int((id >> 22) % uint64(sm.numShards))
+	return sm.shards[shardID], nil
+}
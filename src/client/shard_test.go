@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestShardManager(numShards int) *ShardManager {
+	shards := make([]*Client, numShards)
+	for i := range shards {
+		shards[i] = &Client{shardID: i, numShards: numShards}
+	}
+	return &ShardManager{numShards: numShards, shards: shards}
+}
+
+func TestShardForGuild(t *testing.T) {
+	sm := newTestShardManager(4)
+
+	cases := []struct {
+		guildID Snowflake
+		want    int
+	}{
+		// (guild_id >> 22) % num_shards
+		{guildID: "0", want: 0},
+		{guildID: "4194304", want: 1},  // 1<<22
+		{guildID: "8388608", want: 2},  // 2<<22
+		{guildID: "12582912", want: 3}, // 3<<22
+		{guildID: "16777216", want: 0}, // 4<<22 wraps back to shard 0
+	}
+
+	for _, tc := range cases {
+		shard, err := sm.ShardForGuild(tc.guildID)
+		if err != nil {
+			t.Fatalf("ShardForGuild(%s): %s", tc.guildID, err)
+		}
+		if shard.shardID != tc.want {
+			t.Errorf("ShardForGuild(%s) = shard %d, want %d", tc.guildID, shard.shardID, tc.want)
+		}
+	}
+}
+
+func TestShardForGuildInvalidID(t *testing.T) {
+	sm := newTestShardManager(1)
+
+	if _, err := sm.ShardForGuild("not-a-snowflake"); err == nil {
+		t.Errorf("ShardForGuild() with invalid id succeeded, want error")
+	}
+}
+
+func TestWaitIdentifySlotBucketAssignment(t *testing.T) {
+	sm := newTestShardManager(4)
+	sm.maxConcurrency = 2
+	sm.nextIdentify = make([]time.Time, sm.maxConcurrency)
+
+	// Shards 0 and 2 share bucket 0; shards 1 and 3 share bucket 1.
+	gate0 := sm.waitIdentifySlot(0)
+
+	ctx := context.Background()
+	if err := gate0(ctx); err != nil {
+		t.Fatalf("gate0: %s", err)
+	}
+
+	if sm.nextIdentify[0].IsZero() {
+		t.Errorf("expected bucket 0 to be reserved after shard 0 identified")
+	}
+	if !sm.nextIdentify[1].IsZero() {
+		t.Errorf("expected bucket 1 to be untouched by shard 0's identify")
+	}
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"personal/discord_go/src/opcodes"
+	"personal/discord_go/src/voice"
+
+	"github.com/gorilla/websocket"
+)
+
+// VoiceConnection is a live voice gateway + UDP connection to a guild voice
+// channel, returned by JoinVoiceChannel.
+type VoiceConnection = voice.Connection
+
+// voiceWaitTimeout bounds how long JoinVoiceChannel waits for Discord to
+// send back the VOICE_STATE_UPDATE and VOICE_SERVER_UPDATE dispatch events
+// that carry the session ID, endpoint, and token the voice handshake needs.
+const voiceWaitTimeout = 10 * time.Second
+
+// JoinVoiceChannel moves the bot into a guild voice channel and blocks until
+// the full voice gateway handshake (identify, select protocol, session
+// description) has completed. Pass an empty channelID to leave the current
+// voice channel instead of joining one; it then returns a nil
+// *VoiceConnection and no error as soon as the voice state update is sent.
+func (c *Client) JoinVoiceChannel(guildID, channelID Snowflake, mute, deaf bool) (*VoiceConnection, error) {
+	stateUpdates := make(chan VoiceState, 1)
+	serverUpdates := make(chan VoiceServerUpdateData, 1)
+
+	removeState := c.AddHandler(func(e VoiceStateUpdateEvent) {
+		if e.GuildID == nil || *e.GuildID != guildID || e.UserID != c.getUserID() {
+			return
+		}
+		select {
+		case stateUpdates <- e.VoiceState:
+		default:
+		}
+	})
+	defer removeState()
+
+	removeServer := c.AddHandler(func(e VoiceServerUpdateEvent) {
+		if e.GuildID != guildID {
+			return
+		}
+		select {
+		case serverUpdates <- e.VoiceServerUpdateData:
+		default:
+		}
+	})
+	defer removeServer()
+
+	var channelIDPtr *Snowflake
+	if channelID != "" {
+		channelIDPtr = &channelID
+	}
+
+	if err := c.sendVoiceStateUpdate(guildID, channelIDPtr, mute, deaf); err != nil {
+		return nil, fmt.Errorf("JoinVoiceChannel: %w", err)
+	}
+
+	if channelIDPtr == nil {
+		return nil, nil
+	}
+
+	waitCtx, cancel := context.WithTimeout(context.Background(), voiceWaitTimeout)
+	defer cancel()
+
+	var state VoiceState
+	var server VoiceServerUpdateData
+
+	for state.SessionID == "" || server.Endpoint == nil {
+		select {
+		case state = <-stateUpdates:
+		case server = <-serverUpdates:
+		case <-waitCtx.Done():
+			return nil, fmt.Errorf("JoinVoiceChannel: timed out waiting for voice server info")
+		}
+	}
+
+	return voice.Connect(context.Background(), voice.ConnectParams{
+		Endpoint:  *server.Endpoint,
+		GuildID:   string(guildID),
+		UserID:    string(c.getUserID()),
+		SessionID: state.SessionID,
+		Token:     server.Token,
+	})
+}
+
+func (c *Client) sendVoiceStateUpdate(guildID Snowflake, channelID *Snowflake, mute, deaf bool) error {
+	message := VoiceStateUpdateMessage{
+		Op: opcodes.VoiceStateUpdate,
+		D: VoiceStateUpdateData{
+			GuildID:   guildID,
+			ChannelID: channelID,
+			SelfMute:  mute,
+			SelfDeaf:  deaf,
+		},
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("sendVoiceStateUpdate: could not marshal message: %w", err)
+	}
+
+	if err := c.connection.WriteMessage(websocket.TextMessage, body); err != nil {
+		return fmt.Errorf("sendVoiceStateUpdate: could not send message: %w", err)
+	}
+
+	return nil
+}
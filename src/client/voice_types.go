@@ -0,0 +1,13 @@
+package client
+
+type VoiceStateUpdateMessage struct {
+	Op int                  `json:"op"`
+	D  VoiceStateUpdateData `json:"d"`
+}
+
+type VoiceStateUpdateData struct {
+	GuildID   Snowflake  `json:"guild_id"`
+	ChannelID *Snowflake `json:"channel_id"`
+	SelfMute  bool       `json:"self_mute"`
+	SelfDeaf  bool       `json:"self_deaf"`
+}
@@ -18,6 +18,7 @@ const (
 	Identify
 	PresenceUpdate
 	VoiceStateUpdate
+	_ // 5: Voice Server Ping, deprecated and unused
 	Resume
 	Reconnect
 	RequestGuildMembers
@@ -0,0 +1,269 @@
+package voice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ConnectParams carries everything Connect needs, gathered by the caller
+// from the main gateway's VOICE_STATE_UPDATE and VOICE_SERVER_UPDATE
+// dispatch events.
+type ConnectParams struct {
+	Endpoint  string
+	GuildID   string
+	UserID    string
+	SessionID string
+	Token     string
+}
+
+// Connection is a live voice gateway + UDP connection to a single guild
+// voice channel.
+type Connection struct {
+	GuildID string
+
+	conn *websocket.Conn
+	udp  *udpSession
+
+	ssrc      uint32
+	secretKey [32]byte
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+
+	// OpusSend accepts raw Opus frames to encrypt and send as RTP packets.
+	OpusSend chan []byte
+	// OpusRecv delivers decrypted, decoded RTP packets from other speakers.
+	OpusRecv chan *Packet
+}
+
+// Connect performs the full voice handshake: websocket Identify/Ready,
+// UDP IP discovery, Select Protocol, and Session Description, then starts
+// the heartbeat, send, and receive loops. The returned Connection is ready
+// to stream audio.
+func Connect(ctx context.Context, params ConnectParams) (*Connection, error) {
+	endpoint := "wss://" + strings.TrimSuffix(params.Endpoint, ":443") + "/?v=8"
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, endpoint, http.Header{})
+	if err != nil {
+		return nil, fmt.Errorf("voice.Connect: could not connect to voice gateway: %w", err)
+	}
+
+	vc := &Connection{
+		GuildID:  params.GuildID,
+		conn:     conn,
+		OpusSend: make(chan []byte, 16),
+		OpusRecv: make(chan *Packet, 16),
+	}
+
+	hello, err := vc.readHello()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("voice.Connect: %w", err)
+	}
+
+	if err := vc.sendIdentify(params); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("voice.Connect: %w", err)
+	}
+
+	ready, err := vc.waitForReady()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("voice.Connect: %w", err)
+	}
+	vc.ssrc = ready.SSRC
+
+	udp, err := dialUDP(ready.IP, ready.Port)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("voice.Connect: %w", err)
+	}
+	vc.udp = udp
+
+	externalIP, externalPort, err := udp.discoverIP(ready.SSRC)
+	if err != nil {
+		conn.Close()
+		udp.close()
+		return nil, fmt.Errorf("voice.Connect: %w", err)
+	}
+
+	if err := vc.selectProtocol(externalIP, externalPort); err != nil {
+		conn.Close()
+		udp.close()
+		return nil, fmt.Errorf("voice.Connect: %w", err)
+	}
+
+	secretKey, err := vc.waitForSessionDescription()
+	if err != nil {
+		conn.Close()
+		udp.close()
+		return nil, fmt.Errorf("voice.Connect: %w", err)
+	}
+	vc.secretKey = secretKey
+
+	connCtx, cancel := context.WithCancel(ctx)
+	vc.cancel = cancel
+
+	go vc.heartbeatLoop(connCtx, time.Duration(hello.HeartbeatInterval*float64(time.Millisecond)))
+	go vc.sendLoop(connCtx)
+	go vc.recvLoop(connCtx)
+
+	return vc, nil
+}
+
+func (vc *Connection) readPayload() (payload, error) {
+	var p payload
+	_, body, err := vc.conn.ReadMessage()
+	if err != nil {
+		return p, fmt.Errorf("could not read message: %w", err)
+	}
+	if err := json.Unmarshal(body, &p); err != nil {
+		return p, fmt.Errorf("could not unmarshal payload: %w", err)
+	}
+	return p, nil
+}
+
+func (vc *Connection) writeJSON(v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("could not marshal payload: %w", err)
+	}
+
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	return vc.conn.WriteMessage(websocket.TextMessage, body)
+}
+
+func (vc *Connection) readHello() (helloData, error) {
+	var hello helloData
+
+	p, err := vc.readPayload()
+	if err != nil {
+		return hello, fmt.Errorf("could not read hello: %w", err)
+	}
+	if p.Op != Hello {
+		return hello, fmt.Errorf("expected Hello, got op %d", p.Op)
+	}
+	if err := json.Unmarshal(p.D, &hello); err != nil {
+		return hello, fmt.Errorf("could not unmarshal hello: %w", err)
+	}
+
+	return hello, nil
+}
+
+func (vc *Connection) sendIdentify(params ConnectParams) error {
+	return vc.writeJSON(identifyMessage{
+		Op: Identify,
+		D: identifyData{
+			ServerID:  params.GuildID,
+			UserID:    params.UserID,
+			SessionID: params.SessionID,
+			Token:     params.Token,
+		},
+	})
+}
+
+func (vc *Connection) waitForReady() (readyData, error) {
+	var ready readyData
+
+	p, err := vc.readPayload()
+	if err != nil {
+		return ready, fmt.Errorf("could not read ready: %w", err)
+	}
+	if p.Op != Ready {
+		return ready, fmt.Errorf("expected Ready, got op %d", p.Op)
+	}
+	if err := json.Unmarshal(p.D, &ready); err != nil {
+		return ready, fmt.Errorf("could not unmarshal ready: %w", err)
+	}
+
+	return ready, nil
+}
+
+func (vc *Connection) selectProtocol(externalIP string, externalPort uint16) error {
+	return vc.writeJSON(selectProtocolMessage{
+		Op: SelectProtocol,
+		D: selectProtocolData{
+			Protocol: "udp",
+			Data: selectProtocolInnerData{
+				Address: externalIP,
+				Port:    externalPort,
+				Mode:    encryptionMode,
+			},
+		},
+	})
+}
+
+func (vc *Connection) waitForSessionDescription() ([32]byte, error) {
+	var secretKey [32]byte
+
+	p, err := vc.readPayload()
+	if err != nil {
+		return secretKey, fmt.Errorf("could not read session description: %w", err)
+	}
+	if p.Op != SessionDescription {
+		return secretKey, fmt.Errorf("expected Session Description, got op %d", p.Op)
+	}
+
+	var data sessionDescriptionData
+	if err := json.Unmarshal(p.D, &data); err != nil {
+		return secretKey, fmt.Errorf("could not unmarshal session description: %w", err)
+	}
+	if len(data.SecretKey) != len(secretKey) {
+		return secretKey, fmt.Errorf("unexpected secret key length: %d", len(data.SecretKey))
+	}
+	copy(secretKey[:], data.SecretKey)
+
+	return secretKey, nil
+}
+
+func (vc *Connection) heartbeatLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := vc.writeJSON(heartbeatMessage{Op: Heartbeat, D: time.Now().UnixMilli()}); err != nil {
+				fmt.Printf("voice: heartbeatLoop: could not send heartbeat: %s\n", err)
+				return
+			}
+		}
+	}
+}
+
+// Speaking tells the voice gateway whether this client is currently
+// transmitting audio, which drives the green speaking ring in clients.
+func (vc *Connection) Speaking(speaking bool) error {
+	value := 0
+	if speaking {
+		value = 1
+	}
+
+	return vc.writeJSON(speakingMessage{
+		Op: Speaking,
+		D: speakingData{
+			Speaking: value,
+			SSRC:     vc.ssrc,
+		},
+	})
+}
+
+// Disconnect tears down the voice websocket and UDP connection.
+func (vc *Connection) Disconnect() {
+	if vc.cancel != nil {
+		vc.cancel()
+	}
+	vc.conn.Close()
+	vc.udp.close()
+}
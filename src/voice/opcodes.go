@@ -0,0 +1,32 @@
+package voice
+
+// 0	Identify		Send		Begin a voice websocket connection.
+// 1	Select Protocol		Send		Select the voice protocol.
+// 2	Ready			Receive		Complete the websocket handshake.
+// 3	Heartbeat		Send		Keep the websocket connection alive.
+// 4	Session Description	Receive		Describe the session.
+// 5	Speaking		Send/Receive	Indicate which users are speaking.
+// 6	Heartbeat ACK		Receive		Acknowledge a received client heartbeat.
+// 7	Resume			Send		Resume a connection.
+// 8	Hello			Receive		Time to wait between sending heartbeats.
+// 9	Resumed			Receive		Acknowledge a successful session resume.
+// 11	Clients Connect		Receive		A client has connected to the voice channel.
+// 13	Clients Disconnect	Receive		A client has disconnected from the voice channel.
+
+const (
+	Identify = iota
+	SelectProtocol
+	Ready
+	Heartbeat
+	SessionDescription
+	Speaking
+	HeartbeatACK
+	Resume
+	Hello
+	Resumed
+)
+
+const (
+	ClientsConnect    = 11
+	ClientsDisconnect = 13
+)
@@ -0,0 +1,82 @@
+package voice
+
+import "encoding/json"
+
+// encryptionMode is the only RTP payload encryption mode this package
+// supports; Discord also offers aead_aes256_gcm_rtpsize, but the simpler
+// xsalsa20-poly1305 mode is sufficient for a first implementation.
+const encryptionMode = "xsalsa20_poly1305"
+
+type payload struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d"`
+}
+
+type identifyMessage struct {
+	Op int          `json:"op"`
+	D  identifyData `json:"d"`
+}
+
+type identifyData struct {
+	ServerID  string `json:"server_id"`
+	UserID    string `json:"user_id"`
+	SessionID string `json:"session_id"`
+	Token     string `json:"token"`
+}
+
+type helloData struct {
+	HeartbeatInterval float64 `json:"heartbeat_interval"`
+}
+
+type readyData struct {
+	SSRC  uint32   `json:"ssrc"`
+	IP    string   `json:"ip"`
+	Port  int      `json:"port"`
+	Modes []string `json:"modes"`
+}
+
+type selectProtocolMessage struct {
+	Op int                `json:"op"`
+	D  selectProtocolData `json:"d"`
+}
+
+type selectProtocolData struct {
+	Protocol string                  `json:"protocol"`
+	Data     selectProtocolInnerData `json:"data"`
+}
+
+type selectProtocolInnerData struct {
+	Address string `json:"address"`
+	Port    uint16 `json:"port"`
+	Mode    string `json:"mode"`
+}
+
+type sessionDescriptionData struct {
+	Mode      string `json:"mode"`
+	SecretKey []byte `json:"secret_key"`
+}
+
+type speakingMessage struct {
+	Op int          `json:"op"`
+	D  speakingData `json:"d"`
+}
+
+type speakingData struct {
+	Speaking int    `json:"speaking"`
+	Delay    int    `json:"delay"`
+	SSRC     uint32 `json:"ssrc"`
+}
+
+type heartbeatMessage struct {
+	Op int   `json:"op"`
+	D  int64 `json:"d"`
+}
+
+// Packet is one decoded, decrypted frame of Opus audio received over the
+// voice UDP connection.
+type Packet struct {
+	SSRC      uint32
+	Sequence  uint16
+	Timestamp uint32
+	Opus      []byte
+}
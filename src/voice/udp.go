@@ -0,0 +1,193 @@
+package voice
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+const (
+	rtpHeaderSize = 12
+	rtpVersion    = 0x80
+	rtpPayload    = 0x78
+)
+
+// udpSession is the UDP socket used for RTP audio, plus the running
+// sequence/timestamp state an RTP sender must keep across packets.
+type udpSession struct {
+	conn *net.UDPConn
+
+	sequence  uint16
+	timestamp uint32
+}
+
+func dialUDP(ip string, port int) (*udpSession, error) {
+	addr := &net.UDPAddr{IP: net.ParseIP(ip), Port: port}
+
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, fmt.Errorf("could not open voice UDP socket: %w", err)
+	}
+
+	return &udpSession{conn: conn}, nil
+}
+
+func (u *udpSession) close() {
+	if u != nil && u.conn != nil {
+		u.conn.Close()
+	}
+}
+
+// discoverIP performs Discord's UDP IP discovery handshake: send a 74-byte
+// packet carrying our SSRC, and parse our externally-visible IP and port out
+// of the identically-shaped response.
+// https://discord.com/developers/docs/topics/voice-connections#ip-discovery
+func (u *udpSession) discoverIP(ssrc uint32) (string, uint16, error) {
+	const packetSize = 74
+
+	request := make([]byte, packetSize)
+	binary.BigEndian.PutUint16(request[0:2], 0x1) // request type
+	binary.BigEndian.PutUint16(request[2:4], 70)   // message length
+	binary.BigEndian.PutUint32(request[4:8], ssrc)
+
+	if _, err := u.conn.Write(request); err != nil {
+		return "", 0, fmt.Errorf("could not send ip discovery packet: %w", err)
+	}
+
+	if err := u.conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		return "", 0, fmt.Errorf("could not set ip discovery read deadline: %w", err)
+	}
+	defer u.conn.SetReadDeadline(time.Time{})
+
+	response := make([]byte, packetSize)
+	if _, err := io.ReadFull(u.conn, response); err != nil {
+		return "", 0, fmt.Errorf("could not read ip discovery response: %w", err)
+	}
+
+	ip := bytesToIP(response[8:72])
+	port := binary.BigEndian.Uint16(response[72:74])
+
+	return ip, port, nil
+}
+
+func bytesToIP(b []byte) string {
+	end := len(b)
+	for i, c := range b {
+		if c == 0 {
+			end = i
+			break
+		}
+	}
+	return string(b[:end])
+}
+
+// sendLoop encrypts each Opus frame from OpusSend into an RTP packet and
+// writes it to the voice UDP socket.
+func (vc *Connection) sendLoop(ctx context.Context) {
+	const frameStep = 960 // 20ms at 48kHz, matches Discord's expected cadence
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case opus, ok := <-vc.OpusSend:
+			if !ok {
+				return
+			}
+
+			packet, err := vc.encryptRTP(opus)
+			if err != nil {
+				fmt.Printf("voice: sendLoop: could not encrypt packet: %s\n", err)
+				continue
+			}
+
+			if _, err := vc.udp.conn.Write(packet); err != nil {
+				fmt.Printf("voice: sendLoop: could not write packet: %s\n", err)
+				return
+			}
+
+			vc.udp.sequence++
+			vc.udp.timestamp += frameStep
+		}
+	}
+}
+
+func (vc *Connection) encryptRTP(opus []byte) ([]byte, error) {
+	header := make([]byte, rtpHeaderSize)
+	header[0] = rtpVersion
+	header[1] = rtpPayload
+	binary.BigEndian.PutUint16(header[2:4], vc.udp.sequence)
+	binary.BigEndian.PutUint32(header[4:8], vc.udp.timestamp)
+	binary.BigEndian.PutUint32(header[8:12], vc.ssrc)
+
+	var nonce [24]byte
+	copy(nonce[:], header)
+
+	return secretbox.Seal(header, opus, &nonce, &vc.secretKey), nil
+}
+
+// recvLoop reads RTP packets off the UDP socket, decrypts them, and
+// delivers them on OpusRecv.
+func (vc *Connection) recvLoop(ctx context.Context) {
+	buf := make([]byte, 4096)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		if err := vc.udp.conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			return
+		}
+
+		n, err := vc.udp.conn.Read(buf)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				continue
+			}
+			fmt.Printf("voice: recvLoop: could not read packet: %s\n", err)
+			return
+		}
+		if n < rtpHeaderSize {
+			continue
+		}
+
+		packet, err := vc.decryptRTP(buf[:n])
+		if err != nil {
+			fmt.Printf("voice: recvLoop: could not decrypt packet: %s\n", err)
+			continue
+		}
+
+		select {
+		case vc.OpusRecv <- packet:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (vc *Connection) decryptRTP(data []byte) (*Packet, error) {
+	header := data[:rtpHeaderSize]
+
+	var nonce [24]byte
+	copy(nonce[:], header)
+
+	opus, ok := secretbox.Open(nil, data[rtpHeaderSize:], &nonce, &vc.secretKey)
+	if !ok {
+		return nil, fmt.Errorf("decryption failed")
+	}
+
+	return &Packet{
+		SSRC:      binary.BigEndian.Uint32(header[8:12]),
+		Sequence:  binary.BigEndian.Uint16(header[2:4]),
+		Timestamp: binary.BigEndian.Uint32(header[4:8]),
+		Opus:      opus,
+	}, nil
+}
@@ -0,0 +1,86 @@
+package voice
+
+import "testing"
+
+func TestBytesToIP(t *testing.T) {
+	cases := []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{
+			name: "ip padded with null bytes",
+			in:   append([]byte("123.45.67.89"), make([]byte, 64-len("123.45.67.89"))...),
+			want: "123.45.67.89",
+		},
+		{
+			name: "no padding",
+			in:   []byte("1.2.3.4"),
+			want: "1.2.3.4",
+		},
+		{
+			name: "all null bytes",
+			in:   make([]byte, 8),
+			want: "",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := bytesToIP(tc.in); got != tc.want {
+				t.Errorf("bytesToIP(%v) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEncryptDecryptRTPRoundTrip(t *testing.T) {
+	vc := &Connection{
+		udp: &udpSession{sequence: 42, timestamp: 960},
+		ssrc: 1234,
+	}
+	vc.secretKey[0] = 0x01
+	vc.secretKey[31] = 0xff
+
+	opus := []byte("fake opus frame payload")
+
+	packet, err := vc.encryptRTP(opus)
+	if err != nil {
+		t.Fatalf("encryptRTP: %s", err)
+	}
+
+	got, err := vc.decryptRTP(packet)
+	if err != nil {
+		t.Fatalf("decryptRTP: %s", err)
+	}
+
+	if string(got.Opus) != string(opus) {
+		t.Errorf("decryptRTP().Opus = %q, want %q", got.Opus, opus)
+	}
+	if got.SSRC != vc.ssrc {
+		t.Errorf("decryptRTP().SSRC = %d, want %d", got.SSRC, vc.ssrc)
+	}
+	if got.Sequence != vc.udp.sequence {
+		t.Errorf("decryptRTP().Sequence = %d, want %d", got.Sequence, vc.udp.sequence)
+	}
+	if got.Timestamp != vc.udp.timestamp {
+		t.Errorf("decryptRTP().Timestamp = %d, want %d", got.Timestamp, vc.udp.timestamp)
+	}
+}
+
+func TestDecryptRTPWrongKeyFails(t *testing.T) {
+	vc := &Connection{udp: &udpSession{}, ssrc: 1}
+	vc.secretKey[0] = 0x01
+
+	packet, err := vc.encryptRTP([]byte("hello"))
+	if err != nil {
+		t.Fatalf("encryptRTP: %s", err)
+	}
+
+	other := &Connection{udp: &udpSession{}, ssrc: 1}
+	other.secretKey[0] = 0x02
+
+	if _, err := other.decryptRTP(packet); err == nil {
+		t.Errorf("decryptRTP() with wrong key succeeded, want error")
+	}
+}